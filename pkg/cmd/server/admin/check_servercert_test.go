@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+)
+
+// writeTestServerCert writes a self-signed cert/key pair valid from
+// notBefore to notAfter and returns the cert and key file paths.
+func writeTestServerCert(t *testing.T, notBefore, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating test key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "check.example.com"},
+		DNSNames:     []string{"check.example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating test cert: %v", err)
+	}
+
+	dir := t.TempDir()
+	certPath := dir + "/tls.crt"
+	keyPath := dir + "/tls.key"
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("writing test cert: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshaling test key: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("writing test key: %v", err)
+	}
+	return certPath, keyPath
+}
+
+func TestCheckServerCertOK(t *testing.T) {
+	certPath, keyPath := writeTestServerCert(t, time.Now().Add(-time.Hour), time.Now().Add(365*24*time.Hour))
+	status, err := CheckServerCert(certPath, keyPath, DefaultCertWarningThreshold)
+	if err != nil {
+		t.Fatalf("CheckServerCert: %v", err)
+	}
+	if status.Expired || status.Warning {
+		t.Fatalf("expected a healthy certificate, got Expired=%v Warning=%v", status.Expired, status.Warning)
+	}
+}
+
+func TestCheckServerCertWarning(t *testing.T) {
+	certPath, keyPath := writeTestServerCert(t, time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour))
+	status, err := CheckServerCert(certPath, keyPath, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("CheckServerCert: %v", err)
+	}
+	if status.Expired {
+		t.Fatal("certificate with remaining life should not be Expired")
+	}
+	if !status.Warning {
+		t.Fatal("expected Warning for a certificate expiring within the threshold")
+	}
+}
+
+func TestCheckServerCertExpired(t *testing.T) {
+	certPath, keyPath := writeTestServerCert(t, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour))
+	status, err := CheckServerCert(certPath, keyPath, DefaultCertWarningThreshold)
+	if err != nil {
+		t.Fatalf("CheckServerCert: %v", err)
+	}
+	if !status.Expired {
+		t.Fatal("expected Expired for a certificate past its NotAfter")
+	}
+	if status.DaysRemaining >= 0 {
+		t.Fatalf("expected a negative DaysRemaining for an expired certificate, got %d", status.DaysRemaining)
+	}
+}
@@ -1,8 +1,14 @@
 package admin
 
 import (
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"k8s.io/klog"
@@ -17,17 +23,38 @@ import (
 
 const CreateServerCertCommandName = "create-server-cert"
 
+// keyTypes are the values accepted by the --key-type flag.
+var keyTypes = sets.NewString("rsa", "ecdsa", "ed25519")
+
+// ecdsaCurves are the values accepted by the --ecdsa-curve flag.
+var ecdsaCurves = sets.NewString("P256", "P384")
+
+const minRSABits = 2048
+
 type CreateServerCertOptions struct {
 	SignerCertOptions *SignerCertOptions
 
 	CertFile string
 	KeyFile  string
+	CSRFile  string
 
 	ExpireDays int
 
 	Hostnames []string
 	Overwrite bool
 
+	KeyType    string
+	RSABits    int
+	ECDSACurve string
+
+	URISANs   []string
+	EmailSANs []string
+	SpiffeID  string
+
+	BundleFile         string
+	BundleFormat       string
+	BundlePasswordFile string
+
 	genericclioptions.IOStreams
 }
 
@@ -44,8 +71,8 @@ var createServerLong = templates.LongDesc(`
 			%[1]s --signer-cert=$CA/ca.crt \
 		          --signer-key=$CA/ca.key --signer-serial=$CA/ca.serial.txt \
 		          --hostnames='*.cloudapps.example.com' \
-		          --cert=cloudapps.crt --key=cloudapps.key
-	    cat cloudapps.crt cloudapps.key $CA/ca.crt > cloudapps.router.pem
+		          --cert=cloudapps.crt --key=cloudapps.key \
+		          --bundle=cloudapps.router.pem --bundle-format=pem-fullchain
 	`)
 
 func NewCreateServerCertOptions(streams genericclioptions.IOStreams) *CreateServerCertOptions {
@@ -53,6 +80,10 @@ func NewCreateServerCertOptions(streams genericclioptions.IOStreams) *CreateServ
 		SignerCertOptions: NewDefaultSignerCertOptions(),
 		ExpireDays:        crypto.DefaultCertificateLifetimeInDays,
 		Overwrite:         true,
+		KeyType:           "rsa",
+		RSABits:           minRSABits,
+		ECDSACurve:        "P256",
+		BundleFormat:      "pem-fullchain",
 		IOStreams:         streams,
 	}
 }
@@ -75,15 +106,31 @@ func NewCommandCreateServerCert(commandName string, fullName string, streams gen
 
 	cmd.Flags().StringVar(&o.CertFile, "cert", o.CertFile, "The certificate file. Choose a name that indicates what the service is.")
 	cmd.Flags().StringVar(&o.KeyFile, "key", o.KeyFile, "The key file. Choose a name that indicates what the service is.")
+	cmd.Flags().StringVar(&o.CSRFile, "csr", o.CSRFile, "A PKCS#10 certificate signing request to sign instead of generating a key locally. Mutually exclusive with --key.")
 
 	cmd.Flags().StringSliceVar(&o.Hostnames, "hostnames", o.Hostnames, "Every hostname or IP you want server certs to be valid for. Comma delimited list")
 	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "Overwrite existing cert files if found.  If false, any existing file will be left as-is.")
 
 	cmd.Flags().IntVar(&o.ExpireDays, "expire-days", o.ExpireDays, "Validity of the certificate in days (defaults to 2 years). WARNING: extending this above default value is highly discouraged.")
 
+	cmd.Flags().StringVar(&o.KeyType, "key-type", o.KeyType, "The type of private key to generate. One of: rsa|ecdsa|ed25519.")
+	cmd.Flags().IntVar(&o.RSABits, "rsa-bits", o.RSABits, "The size in bits of the RSA key to generate, when --key-type=rsa (minimum 2048).")
+	cmd.Flags().StringVar(&o.ECDSACurve, "ecdsa-curve", o.ECDSACurve, "The curve of the ECDSA key to generate, when --key-type=ecdsa. One of: P256|P384.")
+
+	cmd.Flags().StringSliceVar(&o.URISANs, "uri-san", o.URISANs, "Every URI you want server certs to be valid for. Comma delimited list")
+	cmd.Flags().StringSliceVar(&o.EmailSANs, "email-san", o.EmailSANs, "Every email address you want server certs to be valid for. Comma delimited list")
+	cmd.Flags().StringVar(&o.SpiffeID, "spiffe-id", o.SpiffeID, "A spiffe://trust-domain/workload URI to add as a SAN.")
+
+	cmd.Flags().StringVar(&o.BundleFile, "bundle", o.BundleFile, "If set, also write a combined cert+key+CA bundle here in --bundle-format, for consumers like the router that want everything in one file.")
+	cmd.Flags().StringVar(&o.BundleFormat, "bundle-format", o.BundleFormat, "The format of --bundle. One of: pem-fullchain|pkcs12|jks.")
+	cmd.Flags().StringVar(&o.BundlePasswordFile, "bundle-password-file", o.BundlePasswordFile, "A file containing the password to protect --bundle with, for pkcs12 and jks formats.")
+
 	// autocompletion hints
 	cmd.MarkFlagFilename("cert")
 	cmd.MarkFlagFilename("key")
+	cmd.MarkFlagFilename("csr")
+	cmd.MarkFlagFilename("bundle")
+	cmd.MarkFlagFilename("bundle-password-file")
 
 	return cmd
 }
@@ -92,14 +139,20 @@ func (o CreateServerCertOptions) Validate(args []string) error {
 	if len(args) != 0 {
 		return errors.New("no arguments are supported")
 	}
-	if len(o.Hostnames) == 0 {
-		return errors.New("at least one hostname must be provided")
-	}
 	if len(o.CertFile) == 0 {
 		return errors.New("cert must be provided")
 	}
-	if len(o.KeyFile) == 0 {
-		return errors.New("key must be provided")
+	if len(o.CSRFile) > 0 {
+		if len(o.KeyFile) > 0 {
+			return errors.New("csr and key are mutually exclusive")
+		}
+	} else {
+		if len(o.KeyFile) == 0 {
+			return errors.New("key must be provided")
+		}
+		if len(o.Hostnames) == 0 {
+			return errors.New("at least one hostname must be provided")
+		}
 	}
 
 	if o.ExpireDays <= 0 {
@@ -113,9 +166,154 @@ func (o CreateServerCertOptions) Validate(args []string) error {
 		return err
 	}
 
+	if err := validateKeySpecFlags(o.KeyType, o.RSABits, o.ECDSACurve); err != nil {
+		return err
+	}
+
+	if _, err := o.uris(); err != nil {
+		return err
+	}
+
+	if len(o.BundleFile) > 0 {
+		if len(o.CSRFile) > 0 {
+			return errors.New("bundle and csr are mutually exclusive: no private key is available to bundle in CSR mode")
+		}
+		found := false
+		for _, format := range bundleFormats {
+			if o.BundleFormat == format {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid bundle-format %q, must be one of: %v", o.BundleFormat, bundleFormats)
+		}
+	}
+
 	return nil
 }
 
+// uris parses --uri-san and --spiffe-id into url.URLs, failing fast on
+// malformed input rather than at signing time. --spiffe-id is a convenience
+// that adds a spiffe:// URI SAN; it does not alter key usages, so the
+// certificate gets defaultKeyUsage and defaultExtKeyUsage like any other.
+func (o CreateServerCertOptions) uris() ([]*url.URL, error) {
+	uris := make([]*url.URL, 0, len(o.URISANs)+1)
+	for _, raw := range o.URISANs {
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --uri-san %q: %v", raw, err)
+		}
+		uris = append(uris, parsed)
+	}
+	if len(o.SpiffeID) > 0 {
+		parsed, err := url.Parse(o.SpiffeID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --spiffe-id %q: %v", o.SpiffeID, err)
+		}
+		if parsed.Scheme != "spiffe" {
+			return nil, fmt.Errorf("invalid --spiffe-id %q: must be a spiffe:// URI", o.SpiffeID)
+		}
+		uris = append(uris, parsed)
+	}
+	return uris, nil
+}
+
+// checkNameConstraints fails early if any requested DNS hostname falls
+// outside the signing CA's PermittedDNSDomains, before any key is generated
+// or certificate signed; x509.CreateCertificate, which does the signing,
+// does not check the CA's name constraints itself.
+func checkNameConstraints(ca *crypto.TLSCertificateConfig, hostnames []string) error {
+	if len(ca.Certs) == 0 {
+		return nil
+	}
+	permitted := ca.Certs[0].PermittedDNSDomains
+	if len(permitted) == 0 {
+		return nil
+	}
+	for _, hostname := range hostnames {
+		if net.ParseIP(hostname) != nil {
+			continue
+		}
+		allowed := false
+		for _, domain := range permitted {
+			if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("hostname %q is not permitted by the signing CA's name constraints %v", hostname, permitted)
+		}
+	}
+	return nil
+}
+
+// keySpec translates the --key-type/--rsa-bits/--ecdsa-curve flags into the
+// KeySpec the signing helpers in this package use to choose a key algorithm.
+func (o CreateServerCertOptions) keySpec() KeySpec {
+	return keySpecFromFlags(o.KeyType, o.RSABits, o.ECDSACurve)
+}
+
+// parseCSRFile reads the PKCS#10 request at csrFile and verifies its
+// self-signature, failing fast before any hostname or name-constraint
+// checks run against it.
+func parseCSRFile(csrFile string) (*x509.CertificateRequest, error) {
+	csrBytes, err := ioutil.ReadFile(csrFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(csrBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no certificate signing request found in %s", csrFile)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("certificate signing request %s has an invalid signature: %v", csrFile, err)
+	}
+	return csr, nil
+}
+
+// mergeCSRHostnames merges hostnames with the DNS names and IP addresses the
+// CSR itself requests, so a CSR that already carries its own SANs can be
+// signed with --csr/--cert and no additional --hostnames.
+func mergeCSRHostnames(hostnames []string, csr *x509.CertificateRequest) sets.String {
+	merged := sets.NewString(hostnames...)
+	merged.Insert(csr.DNSNames...)
+	for _, ip := range csr.IPAddresses {
+		merged.Insert(ip.String())
+	}
+	return merged
+}
+
+// csrCommonName returns the CSR's own Subject common name if it set one,
+// falling back to the alphabetically-first hostname so a signed CSR always
+// ends up with a Subject.
+func csrCommonName(csr *x509.CertificateRequest, merged sets.String) string {
+	if len(csr.Subject.CommonName) > 0 {
+		return csr.Subject.CommonName
+	}
+	if list := merged.List(); len(list) > 0 {
+		return list[0]
+	}
+	return ""
+}
+
+// signCSRFile signs the already-parsed CSR against the merged hostname set,
+// combining extraSANs with whatever URI/email SANs the CSR itself requested.
+// No private key is generated or written: the caller already holds the key
+// that produced the CSR.
+func signCSRFile(signerCert *crypto.TLSCertificateConfig, certFile string, csr *x509.CertificateRequest, merged sets.String, expireDays int, extraSANs ExtraSANs) (*crypto.TLSCertificateConfig, error) {
+	combined := ExtraSANs{
+		URIs:           append(append([]*url.URL{}, extraSANs.URIs...), csr.URIs...),
+		EmailAddresses: append(append([]string{}, extraSANs.EmailAddresses...), csr.EmailAddresses...),
+	}
+	return signCertificateRequest(signerCert, certFile, csr.PublicKey, merged, csrCommonName(csr, merged), expireDays, combined, defaultKeyUsage, defaultExtKeyUsage)
+}
+
 func (o CreateServerCertOptions) CreateServerCert() (*crypto.TLSCertificateConfig, error) {
 	klog.V(4).Infof("Creating a server cert with: %#v", o)
 
@@ -124,17 +322,79 @@ func (o CreateServerCertOptions) CreateServerCert() (*crypto.TLSCertificateConfi
 		return nil, err
 	}
 
+	uris, err := o.uris()
+	if err != nil {
+		return nil, err
+	}
+	extraSANs := ExtraSANs{URIs: uris, EmailAddresses: o.EmailSANs}
+
+	if len(o.CSRFile) > 0 {
+		csr, err := parseCSRFile(o.CSRFile)
+		if err != nil {
+			return nil, err
+		}
+		merged := mergeCSRHostnames(o.Hostnames, csr)
+		if merged.Len() == 0 {
+			return nil, fmt.Errorf("certificate signing request %s has no DNS or IP SANs and no --hostnames were given", o.CSRFile)
+		}
+		if err := checkNameConstraints(signerCert, merged.List()); err != nil {
+			return nil, err
+		}
+		ca, err := signCSRFile(signerCert, o.CertFile, csr, merged, o.ExpireDays, extraSANs)
+		if err != nil {
+			return nil, err
+		}
+		klog.V(3).Infof("Signed CSR %s into certificate %s\n", o.CSRFile, o.CertFile)
+		return ca, nil
+	}
+
+	if err := checkNameConstraints(signerCert, o.Hostnames); err != nil {
+		return nil, err
+	}
+
+	hostnames := sets.NewString([]string(o.Hostnames)...)
+	var commonName string
+	if list := hostnames.List(); len(list) > 0 {
+		commonName = list[0]
+	}
+
 	var ca *crypto.TLSCertificateConfig
 	written := true
 	if o.Overwrite {
-		ca, err = signerCert.MakeAndWriteServerCert(o.CertFile, o.KeyFile, sets.NewString([]string(o.Hostnames)...), o.ExpireDays)
+		ca, err = makeAndWriteServerCertWithKeySpec(signerCert, o.CertFile, o.KeyFile, hostnames, commonName, o.ExpireDays, o.keySpec(), extraSANs, defaultKeyUsage, defaultExtKeyUsage)
 	} else {
-		ca, written, err = signerCert.EnsureServerCert(o.CertFile, o.KeyFile, sets.NewString([]string(o.Hostnames)...), o.ExpireDays)
+		ca, written, err = ensureServerCertWithKeySpec(signerCert, o.CertFile, o.KeyFile, hostnames, commonName, o.ExpireDays, o.keySpec(), extraSANs, defaultKeyUsage, defaultExtKeyUsage)
 	}
 	if written {
 		klog.V(3).Infof("Generated new server certificate as %s, key as %s\n", o.CertFile, o.KeyFile)
 	} else {
 		klog.V(3).Infof("Keeping existing server certificate at %s, key at %s\n", o.CertFile, o.KeyFile)
 	}
-	return ca, err
+	if err != nil {
+		return ca, err
+	}
+
+	if len(o.BundleFile) > 0 {
+		if err := o.writeBundle(ca); err != nil {
+			return ca, err
+		}
+	}
+
+	return ca, nil
+}
+
+func (o CreateServerCertOptions) writeBundle(ca *crypto.TLSCertificateConfig) error {
+	password, err := readBundlePassword(o.BundlePasswordFile)
+	if err != nil {
+		return err
+	}
+	bundle, err := assembleBundle(o.BundleFormat, ca.Certs, ca.Key, password)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(o.BundleFile, bundle, 0600); err != nil {
+		return err
+	}
+	klog.V(3).Infof("Wrote %s bundle as %s\n", o.BundleFormat, o.BundleFile)
+	return nil
 }
@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"os"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/library-go/pkg/crypto"
+)
+
+func caWithPermittedDNSDomains(domains ...string) *crypto.TLSCertificateConfig {
+	return &crypto.TLSCertificateConfig{
+		Certs: []*x509.Certificate{{PermittedDNSDomains: domains}},
+	}
+}
+
+func TestCheckNameConstraintsNoConstraints(t *testing.T) {
+	if err := checkNameConstraints(&crypto.TLSCertificateConfig{}, []string{"anything.example.com"}); err != nil {
+		t.Fatalf("expected no error when the CA has no certs, got %v", err)
+	}
+	if err := checkNameConstraints(caWithPermittedDNSDomains(), []string{"anything.example.com"}); err != nil {
+		t.Fatalf("expected no error when PermittedDNSDomains is empty, got %v", err)
+	}
+}
+
+func TestCheckNameConstraintsAllowed(t *testing.T) {
+	ca := caWithPermittedDNSDomains("example.com")
+	for _, hostname := range []string{"example.com", "foo.example.com", "10.0.0.1"} {
+		if err := checkNameConstraints(ca, []string{hostname}); err != nil {
+			t.Errorf("expected %q to be permitted, got error: %v", hostname, err)
+		}
+	}
+}
+
+func TestCheckNameConstraintsRejected(t *testing.T) {
+	ca := caWithPermittedDNSDomains("example.com")
+	if err := checkNameConstraints(ca, []string{"evil.com"}); err == nil {
+		t.Fatal("expected hostname outside the permitted domains to be rejected")
+	}
+}
+
+func TestMergeCSRHostnames(t *testing.T) {
+	csr := &x509.CertificateRequest{
+		DNSNames:    []string{"csr.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.5")},
+	}
+	merged := mergeCSRHostnames([]string{"flag.example.com"}, csr)
+	for _, want := range []string{"csr.example.com", "10.0.0.5", "flag.example.com"} {
+		if !merged.Has(want) {
+			t.Errorf("expected merged set to contain %q, got %v", want, merged.List())
+		}
+	}
+}
+
+func TestMergeCSRHostnamesNoHostnamesFlag(t *testing.T) {
+	csr := &x509.CertificateRequest{DNSNames: []string{"csr-only.example.com"}}
+	merged := mergeCSRHostnames(nil, csr)
+	if !merged.Equal(sets.NewString("csr-only.example.com")) {
+		t.Fatalf("expected merge with no --hostnames to carry only the CSR's own SANs, got %v", merged.List())
+	}
+}
+
+// writeTestCSR generates an ECDSA key and CSR for dnsNames and writes the
+// PEM-encoded request to a file under t.TempDir, returning its path.
+func writeTestCSR(t *testing.T, dnsNames ...string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating test key: %v", err)
+	}
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: dnsNames[0]},
+		DNSNames: dnsNames,
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatalf("creating test CSR: %v", err)
+	}
+	path := t.TempDir() + "/test.csr"
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+	if err := os.WriteFile(path, pemBytes, 0644); err != nil {
+		t.Fatalf("writing test CSR: %v", err)
+	}
+	return path
+}
+
+func TestParseCSRFileValid(t *testing.T) {
+	path := writeTestCSR(t, "csr.example.com")
+	csr, err := parseCSRFile(path)
+	if err != nil {
+		t.Fatalf("parseCSRFile: %v", err)
+	}
+	if csr.DNSNames[0] != "csr.example.com" {
+		t.Fatalf("expected DNS name csr.example.com, got %v", csr.DNSNames)
+	}
+}
+
+func TestParseCSRFileNotPEM(t *testing.T) {
+	path := t.TempDir() + "/bad.csr"
+	if err := os.WriteFile(path, []byte("not a csr"), 0644); err != nil {
+		t.Fatalf("writing test file: %v", err)
+	}
+	if _, err := parseCSRFile(path); err == nil {
+		t.Fatal("expected an error for a non-PEM file")
+	}
+}
+
+func TestParseCSRFileTamperedSignature(t *testing.T) {
+	path := writeTestCSR(t, "csr.example.com")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading test CSR: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	block.Bytes[len(block.Bytes)-1] ^= 0xFF
+	tamperedPath := path + ".tampered"
+	if err := os.WriteFile(tamperedPath, pem.EncodeToMemory(block), 0644); err != nil {
+		t.Fatalf("writing tampered CSR: %v", err)
+	}
+	if _, err := parseCSRFile(tamperedPath); err == nil {
+		t.Fatal("expected a tampered CSR signature to fail verification")
+	}
+}
@@ -0,0 +1,163 @@
+package admin
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+const CreateServerCSRCommandName = "create-server-csr"
+
+type CreateServerCSROptions struct {
+	CSRFile string
+	KeyFile string
+
+	Hostnames []string
+
+	KeyType    string
+	RSABits    int
+	ECDSACurve string
+
+	genericclioptions.IOStreams
+}
+
+var createServerCSRLong = templates.LongDesc(`
+	Create a key and certificate signing request
+
+	Create a private key and a PKCS#10 certificate signing request for the
+	given hostnames, for submission to an external CA. The key never leaves
+	this machine; pair the resulting CSR with %[2]s --csr to sign it, or
+	submit it to an external CA.
+
+	Example: Requesting a router certificate from an external CA.
+
+	    %[1]s --hostnames='*.cloudapps.example.com' \
+	          --csr=cloudapps.csr --key=cloudapps.key
+	`)
+
+func NewCreateServerCSROptions(streams genericclioptions.IOStreams) *CreateServerCSROptions {
+	return &CreateServerCSROptions{
+		KeyType:    "rsa",
+		RSABits:    minRSABits,
+		ECDSACurve: "P256",
+		IOStreams:  streams,
+	}
+}
+
+func NewCommandCreateServerCSR(commandName string, fullName string, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewCreateServerCSROptions(streams)
+	cmd := &cobra.Command{
+		Use:   commandName,
+		Short: "Create a certificate signing request and key",
+		Long:  fmt.Sprintf(createServerCSRLong, fullName, CreateServerCertCommandName),
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Validate(args))
+			kcmdutil.CheckErr(o.CreateServerCSR())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.CSRFile, "csr", o.CSRFile, "The certificate signing request file.")
+	cmd.Flags().StringVar(&o.KeyFile, "key", o.KeyFile, "The key file.")
+	cmd.Flags().StringSliceVar(&o.Hostnames, "hostnames", o.Hostnames, "Every hostname or IP you want the certificate to be valid for. Comma delimited list")
+
+	cmd.Flags().StringVar(&o.KeyType, "key-type", o.KeyType, "The type of private key to generate. One of: rsa|ecdsa|ed25519.")
+	cmd.Flags().IntVar(&o.RSABits, "rsa-bits", o.RSABits, "The size in bits of the RSA key to generate, when --key-type=rsa (minimum 2048).")
+	cmd.Flags().StringVar(&o.ECDSACurve, "ecdsa-curve", o.ECDSACurve, "The curve of the ECDSA key to generate, when --key-type=ecdsa. One of: P256|P384.")
+
+	cmd.MarkFlagFilename("csr")
+	cmd.MarkFlagFilename("key")
+
+	return cmd
+}
+
+func (o CreateServerCSROptions) Validate(args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments are supported")
+	}
+	if len(o.Hostnames) == 0 {
+		return errors.New("at least one hostname must be provided")
+	}
+	if len(o.CSRFile) == 0 {
+		return errors.New("csr must be provided")
+	}
+	if len(o.KeyFile) == 0 {
+		return errors.New("key must be provided")
+	}
+	return validateKeySpecFlags(o.KeyType, o.RSABits, o.ECDSACurve)
+}
+
+// keySpec translates the --key-type/--rsa-bits/--ecdsa-curve flags into the
+// KeySpec the signing helpers in this package use to choose a key algorithm.
+func (o CreateServerCSROptions) keySpec() KeySpec {
+	return keySpecFromFlags(o.KeyType, o.RSABits, o.ECDSACurve)
+}
+
+// CreateServerCSR generates a private key matching o.keySpec and a PKCS#10
+// certificate signing request for o.Hostnames, writing the key to o.KeyFile
+// and the CSR to o.CSRFile. No certificate is signed; pair with
+// CreateServerCertOptions.CreateServerCert's --csr mode or an external CA.
+func (o CreateServerCSROptions) CreateServerCSR() error {
+	klog.V(4).Infof("Creating a server CSR with: %#v", o)
+
+	key, err := generatePrivateKey(o.keySpec())
+	if err != nil {
+		return err
+	}
+
+	hostnames := sets.NewString(o.Hostnames...)
+	dnsNames, ipAddresses := splitHostnames(hostnames)
+	template := &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: hostnames.List()[0]},
+		DNSNames:    dnsNames,
+		IPAddresses: ipAddresses,
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(cryptorand.Reader, template, key)
+	if err != nil {
+		return err
+	}
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+	if err := ioutil.WriteFile(o.CSRFile, csrPEM, 0644); err != nil {
+		return err
+	}
+
+	keyPEM, err := marshalPrivateKeyPEM(key)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(o.KeyFile, keyPEM, 0600); err != nil {
+		return err
+	}
+
+	klog.V(3).Infof("Generated certificate signing request as %s, key as %s\n", o.CSRFile, o.KeyFile)
+	return nil
+}
+
+// splitHostnames partitions a hostname set into DNS names and IP addresses,
+// mirroring how the signing helpers in the crypto package interpret --hostnames.
+func splitHostnames(hostnames sets.String) ([]string, []net.IP) {
+	var dnsNames []string
+	var ipAddresses []net.IP
+	for _, hostname := range hostnames.List() {
+		if ip := net.ParseIP(hostname); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+		} else {
+			dnsNames = append(dnsNames, hostname)
+		}
+	}
+	return dnsNames, ipAddresses
+}
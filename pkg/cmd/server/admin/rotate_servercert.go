@@ -0,0 +1,273 @@
+package admin
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+
+	"github.com/openshift/library-go/pkg/crypto"
+)
+
+const RotateServerCertCommandName = "rotate-server-cert"
+
+type RotateServerCertOptions struct {
+	SignerCertOptions *SignerCertOptions
+
+	CertFile string
+	KeyFile  string
+
+	ExpireDays int
+
+	Hostnames []string
+
+	OnlyIfExpiringWithin time.Duration
+	BackupDir            string
+
+	genericclioptions.IOStreams
+}
+
+var rotateServerLong = templates.LongDesc(`
+	Re-issue a key and server certificate in place
+
+	Reads the existing certificate at --cert, re-signs it with the given
+	signer while preserving its Subject common name, SANs, key type, and key
+	usages (--hostnames overrides only the DNS/IP names carried forward), and
+	atomically replaces the cert and key. Combine with
+	--only-if-expiring-within to make this a no-op for cron-driven rotation
+	when the certificate still has enough life left.
+
+	Example: Rotating a router certificate that is close to expiring.
+
+	    CA=openshift.local.config/master
+	        %[1]s --signer-cert=$CA/ca.crt \
+	              --signer-key=$CA/ca.key --signer-serial=$CA/ca.serial.txt \
+	              --cert=cloudapps.crt --key=cloudapps.key \
+	              --only-if-expiring-within=720h
+	`)
+
+func NewRotateServerCertOptions(streams genericclioptions.IOStreams) *RotateServerCertOptions {
+	return &RotateServerCertOptions{
+		SignerCertOptions: NewDefaultSignerCertOptions(),
+		ExpireDays:        crypto.DefaultCertificateLifetimeInDays,
+		IOStreams:         streams,
+	}
+}
+
+func NewCommandRotateServerCert(commandName string, fullName string, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewRotateServerCertOptions(streams)
+	cmd := &cobra.Command{
+		Use:   commandName,
+		Short: "Re-issue a signed server certificate and key in place",
+		Long:  fmt.Sprintf(rotateServerLong, fullName),
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Validate(args))
+			kcmdutil.CheckErr(o.RotateServerCert())
+		},
+	}
+
+	BindSignerCertOptions(o.SignerCertOptions, cmd.Flags(), "")
+
+	cmd.Flags().StringVar(&o.CertFile, "cert", o.CertFile, "The certificate file to rotate.")
+	cmd.Flags().StringVar(&o.KeyFile, "key", o.KeyFile, "The key file to rotate.")
+	cmd.Flags().StringSliceVar(&o.Hostnames, "hostnames", o.Hostnames, "Override the hostnames carried over from the existing certificate. Comma delimited list")
+	cmd.Flags().IntVar(&o.ExpireDays, "expire-days", o.ExpireDays, "Validity of the new certificate in days (defaults to 2 years). WARNING: extending this above default value is highly discouraged.")
+	cmd.Flags().DurationVar(&o.OnlyIfExpiringWithin, "only-if-expiring-within", o.OnlyIfExpiringWithin, "Skip rotation if the existing certificate has more than this much life remaining. Zero always rotates.")
+	cmd.Flags().StringVar(&o.BackupDir, "backup-dir", o.BackupDir, "If set, copy the prior cert and key here before overwriting them.")
+
+	cmd.MarkFlagFilename("cert")
+	cmd.MarkFlagFilename("key")
+	cmd.MarkFlagFilename("backup-dir")
+
+	return cmd
+}
+
+func (o RotateServerCertOptions) Validate(args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments are supported")
+	}
+	if len(o.CertFile) == 0 {
+		return errors.New("cert must be provided")
+	}
+	if len(o.KeyFile) == 0 {
+		return errors.New("key must be provided")
+	}
+	if o.ExpireDays <= 0 {
+		return errors.New("expire-days must be valid number of days")
+	}
+	if o.SignerCertOptions == nil {
+		return errors.New("signer options are required")
+	}
+	if err := o.SignerCertOptions.Validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RotateServerCert re-signs the certificate at o.CertFile, preserving its
+// existing key type, SANs (DNS, IP, URI, and email), key usages, and Subject
+// common name unless o.Hostnames overrides the DNS/IP names, and atomically
+// swaps the cert and key in place. It is a no-op if the existing certificate
+// still has more than o.OnlyIfExpiringWithin of life remaining.
+func (o RotateServerCertOptions) RotateServerCert() error {
+	klog.V(4).Infof("Rotating a server cert with: %#v", o)
+
+	existing, err := readExistingCertAttrs(o.CertFile)
+	if err != nil {
+		return err
+	}
+
+	hostnames := existing.Hostnames
+	if len(o.Hostnames) > 0 {
+		hostnames = sets.NewString(o.Hostnames...)
+	}
+	if hostnames.Len() == 0 {
+		return fmt.Errorf("certificate %s has no SANs to preserve, pass --hostnames", o.CertFile)
+	}
+
+	if o.OnlyIfExpiringWithin > 0 {
+		remaining, err := remainingLifetime(o.CertFile)
+		if err != nil {
+			return err
+		}
+		if remaining > o.OnlyIfExpiringWithin {
+			klog.V(3).Infof("Certificate %s has %s remaining, skipping rotation", o.CertFile, remaining)
+			return nil
+		}
+	}
+
+	if len(o.BackupDir) > 0 {
+		if err := backupFile(o.CertFile, o.BackupDir); err != nil {
+			return err
+		}
+		if err := backupFile(o.KeyFile, o.BackupDir); err != nil {
+			return err
+		}
+	}
+
+	signerCert, err := o.SignerCertOptions.CA()
+	if err != nil {
+		return err
+	}
+
+	tmpCert := o.CertFile + ".tmp"
+	tmpKey := o.KeyFile + ".tmp"
+	if _, err := makeAndWriteServerCertWithKeySpec(signerCert, tmpCert, tmpKey, hostnames, existing.CommonName, o.ExpireDays, existing.KeySpec, existing.ExtraSANs, existing.KeyUsage, existing.ExtKeyUsage); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpCert, o.CertFile); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpKey, o.KeyFile); err != nil {
+		return err
+	}
+
+	klog.V(3).Infof("Rotated server certificate as %s, key as %s\n", o.CertFile, o.KeyFile)
+	return nil
+}
+
+// existingCertAttrs carries forward everything about a certificate that
+// RotateServerCert should preserve across re-signing: its Subject common
+// name, its SANs (DNS, IP, URI, and email), its key usages, and the key type
+// it was originally issued with. CommonName is tracked separately from
+// Hostnames so a CN that isn't itself one of the cert's DNS/IP SANs (common
+// on older or hand-issued certs) is preserved as the Subject rather than
+// silently promoted into a new SAN.
+type existingCertAttrs struct {
+	CommonName  string
+	Hostnames   sets.String
+	ExtraSANs   ExtraSANs
+	KeySpec     KeySpec
+	KeyUsage    x509.KeyUsage
+	ExtKeyUsage []x509.ExtKeyUsage
+}
+
+func readExistingCertAttrs(certFile string) (existingCertAttrs, error) {
+	cert, err := readCertFile(certFile)
+	if err != nil {
+		return existingCertAttrs{}, err
+	}
+
+	hostnames := sets.NewString(cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		hostnames.Insert(ip.String())
+	}
+
+	return existingCertAttrs{
+		CommonName:  cert.Subject.CommonName,
+		Hostnames:   hostnames,
+		ExtraSANs:   ExtraSANs{URIs: cert.URIs, EmailAddresses: cert.EmailAddresses},
+		KeySpec:     keySpecForPublicKey(cert.PublicKey),
+		KeyUsage:    cert.KeyUsage,
+		ExtKeyUsage: cert.ExtKeyUsage,
+	}, nil
+}
+
+// keySpecForPublicKey infers the KeySpec that would reproduce the algorithm
+// (and, for RSA/ECDSA, the size/curve) of an existing certificate's key, so
+// rotation re-issues with the same key type instead of quietly falling back
+// to the default RSA key.
+func keySpecForPublicKey(pub interface{}) KeySpec {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		curve := "P256"
+		if pub.Curve == elliptic.P384() {
+			curve = "P384"
+		}
+		return KeySpec{Type: "ecdsa", ECDSACurve: curve}
+	case ed25519.PublicKey:
+		return KeySpec{Type: "ed25519"}
+	case *rsa.PublicKey:
+		return KeySpec{Type: "rsa", RSABits: pub.N.BitLen()}
+	default:
+		return KeySpec{Type: "rsa", RSABits: minRSABits}
+	}
+}
+
+func remainingLifetime(certFile string) (time.Duration, error) {
+	cert, err := readCertFile(certFile)
+	if err != nil {
+		return 0, err
+	}
+	return time.Until(cert.NotAfter), nil
+}
+
+func readCertFile(certFile string) (*x509.Certificate, error) {
+	certBytes, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(certBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no certificate found in %s", certFile)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func backupFile(src, backupDir string) error {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	dst := filepath.Join(backupDir, filepath.Base(src))
+	return ioutil.WriteFile(dst, data, 0600)
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeySpecForPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating ECDSA key: %v", err)
+	}
+	ed25519Pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating Ed25519 key: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		pub  interface{}
+		want KeySpec
+	}{
+		{"rsa", &rsaKey.PublicKey, KeySpec{Type: "rsa", RSABits: 2048}},
+		{"ecdsa-p384", &ecdsaKey.PublicKey, KeySpec{Type: "ecdsa", ECDSACurve: "P384"}},
+		{"ed25519", ed25519Pub, KeySpec{Type: "ed25519"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := keySpecForPublicKey(c.pub)
+			if got != c.want {
+				t.Errorf("keySpecForPublicKey(%s) = %+v, want %+v", c.name, got, c.want)
+			}
+		})
+	}
+}
+
+func TestReadExistingCertAttrsPreservesSANsAndKeyType(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating test key: %v", err)
+	}
+	spiffeURI, err := url.Parse("spiffe://example.org/ns/default/sa/router")
+	if err != nil {
+		t.Fatalf("parsing test URI: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(1),
+		Subject:        pkix.Name{CommonName: "router.example.com"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().Add(time.Hour),
+		DNSNames:       []string{"router.example.com", "alt.example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.9")},
+		URIs:           []*url.URL{spiffeURI},
+		EmailAddresses: []string{"admin@example.com"},
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating test cert: %v", err)
+	}
+	certPath := t.TempDir() + "/rotate.crt"
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("writing test cert: %v", err)
+	}
+
+	attrs, err := readExistingCertAttrs(certPath)
+	if err != nil {
+		t.Fatalf("readExistingCertAttrs: %v", err)
+	}
+
+	for _, want := range []string{"router.example.com", "alt.example.com", "10.0.0.9"} {
+		if !attrs.Hostnames.Has(want) {
+			t.Errorf("expected preserved hostnames to contain %q, got %v", want, attrs.Hostnames.List())
+		}
+	}
+	if len(attrs.ExtraSANs.URIs) != 1 || attrs.ExtraSANs.URIs[0].String() != spiffeURI.String() {
+		t.Errorf("expected the URI SAN to be preserved, got %v", attrs.ExtraSANs.URIs)
+	}
+	if len(attrs.ExtraSANs.EmailAddresses) != 1 || attrs.ExtraSANs.EmailAddresses[0] != "admin@example.com" {
+		t.Errorf("expected the email SAN to be preserved, got %v", attrs.ExtraSANs.EmailAddresses)
+	}
+	wantSpec := KeySpec{Type: "ecdsa", ECDSACurve: "P256"}
+	if attrs.KeySpec != wantSpec {
+		t.Errorf("expected key spec %+v, got %+v", wantSpec, attrs.KeySpec)
+	}
+	if attrs.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Errorf("expected key usage %v to be preserved, got %v", x509.KeyUsageDigitalSignature, attrs.KeyUsage)
+	}
+	wantExtKeyUsage := []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	if !reflect.DeepEqual(attrs.ExtKeyUsage, wantExtKeyUsage) {
+		t.Errorf("expected ext key usages %v to be preserved, got %v", wantExtKeyUsage, attrs.ExtKeyUsage)
+	}
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+	pkcs12 "software.sslmate.com/src/go-pkcs12"
+)
+
+func selfSignedTestCert(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating test key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "bundle.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"bundle.example.com"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating test cert: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing test cert: %v", err)
+	}
+	return cert, key
+}
+
+func TestAssembleBundleEmptyChain(t *testing.T) {
+	if _, err := assembleBundle("pem-fullchain", nil, nil, nil); err == nil {
+		t.Fatal("expected an error for an empty chain, got nil")
+	}
+}
+
+func TestAssembleBundleInvalidFormat(t *testing.T) {
+	cert, key := selfSignedTestCert(t)
+	if _, err := assembleBundle("der", []*x509.Certificate{cert}, key, nil); err == nil {
+		t.Fatal("expected an error for an invalid bundle format, got nil")
+	}
+}
+
+func TestAssemblePEMFullChain(t *testing.T) {
+	cert, key := selfSignedTestCert(t)
+	bundle, err := assemblePEMFullChain([]*x509.Certificate{cert}, key)
+	if err != nil {
+		t.Fatalf("assemblePEMFullChain: %v", err)
+	}
+
+	var blocks []*pem.Block
+	rest := bundle
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 PEM blocks (cert, key), got %d", len(blocks))
+	}
+	if blocks[0].Type != "CERTIFICATE" {
+		t.Errorf("expected first block to be CERTIFICATE, got %s", blocks[0].Type)
+	}
+	if blocks[1].Type != "PRIVATE KEY" {
+		t.Errorf("expected second block to be PRIVATE KEY, got %s", blocks[1].Type)
+	}
+}
+
+func TestAssemblePKCS12(t *testing.T) {
+	cert, key := selfSignedTestCert(t)
+	password := []byte("s3cr3t")
+	bundle, err := assembleBundle("pkcs12", []*x509.Certificate{cert}, key, password)
+	if err != nil {
+		t.Fatalf("assembleBundle(pkcs12): %v", err)
+	}
+
+	decodedKey, decodedCert, err := pkcs12.Decode(bundle, string(password))
+	if err != nil {
+		t.Fatalf("decoding generated PKCS#12 bundle: %v", err)
+	}
+	if !decodedCert.Equal(cert) {
+		t.Fatal("decoded certificate does not match the original")
+	}
+	if !decodedKey.(*rsa.PrivateKey).Equal(key) {
+		t.Fatal("decoded private key does not match the original")
+	}
+}
+
+func TestAssembleJKS(t *testing.T) {
+	cert, key := selfSignedTestCert(t)
+	password := []byte("s3cr3t")
+	bundle, err := assembleJKS([]*x509.Certificate{cert}, key, password)
+	if err != nil {
+		t.Fatalf("assembleJKS: %v", err)
+	}
+
+	ks := keystore.New()
+	if err := ks.Load(bytes.NewReader(bundle), password); err != nil {
+		t.Fatalf("loading generated JKS keystore: %v", err)
+	}
+	entry, err := ks.GetPrivateKeyEntry("server", password)
+	if err != nil {
+		t.Fatalf("reading back private key entry: %v", err)
+	}
+	if len(entry.CertificateChain) != 1 {
+		t.Fatalf("expected 1 certificate in the chain, got %d", len(entry.CertificateChain))
+	}
+}
+
+func TestReadBundlePassword(t *testing.T) {
+	if password, err := readBundlePassword(""); err != nil || password != nil {
+		t.Fatalf("expected nil password and no error for an empty path, got %q, %v", password, err)
+	}
+
+	dir := t.TempDir()
+	path := dir + "/password.txt"
+	if err := os.WriteFile(path, []byte("hunter2\r\n"), 0600); err != nil {
+		t.Fatalf("writing test password file: %v", err)
+	}
+	password, err := readBundlePassword(path)
+	if err != nil {
+		t.Fatalf("readBundlePassword: %v", err)
+	}
+	if string(password) != "hunter2" {
+		t.Fatalf("expected trailing CRLF to be trimmed, got %q", password)
+	}
+}
@@ -0,0 +1,312 @@
+package admin
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	libcrypto "github.com/openshift/library-go/pkg/crypto"
+)
+
+// KeySpec fully describes the private key to generate for a server
+// certificate or CSR: its algorithm and, for RSA/ECDSA, the size or curve.
+// It is built from the --key-type/--rsa-bits/--ecdsa-curve flags shared by
+// create-server-cert and create-server-csr via keySpecFromFlags.
+type KeySpec struct {
+	Type       string // one of the keyTypes values: "rsa", "ecdsa", "ed25519"
+	RSABits    int
+	ECDSACurve string
+}
+
+// ExtraSANs holds additional Subject Alternative Names beyond the DNS/IP
+// hostnames, merged into the certificate template during signing.
+type ExtraSANs struct {
+	URIs           []*url.URL
+	EmailAddresses []string
+}
+
+// validateKeySpecFlags validates the --key-type/--rsa-bits/--ecdsa-curve
+// flags shared by create-server-cert and create-server-csr, so the two
+// commands can't drift apart on what combinations they accept.
+func validateKeySpecFlags(keyType string, rsaBits int, ecdsaCurve string) error {
+	if !keyTypes.Has(keyType) {
+		return fmt.Errorf("invalid key-type %q, must be one of: %v", keyType, keyTypes.List())
+	}
+	switch keyType {
+	case "rsa":
+		if rsaBits < minRSABits {
+			return fmt.Errorf("rsa-bits must be at least %d", minRSABits)
+		}
+	case "ecdsa":
+		if !ecdsaCurves.Has(ecdsaCurve) {
+			return fmt.Errorf("invalid ecdsa-curve %q, must be one of: %v", ecdsaCurve, ecdsaCurves.List())
+		}
+	case "ed25519":
+		if rsaBits != minRSABits {
+			return errors.New("rsa-bits cannot be combined with --key-type=ed25519")
+		}
+	}
+	return nil
+}
+
+// keySpecFromFlags builds a KeySpec from the --key-type/--rsa-bits/
+// --ecdsa-curve flags shared by create-server-cert and create-server-csr.
+func keySpecFromFlags(keyType string, rsaBits int, ecdsaCurve string) KeySpec {
+	switch keyType {
+	case "ecdsa":
+		return KeySpec{Type: "ecdsa", ECDSACurve: ecdsaCurve}
+	case "ed25519":
+		return KeySpec{Type: "ed25519"}
+	default:
+		return KeySpec{Type: "rsa", RSABits: rsaBits}
+	}
+}
+
+// generatePrivateKey creates a new private key matching spec. library-go's
+// signing helpers only ever generate RSA keys, so create-server-cert,
+// rotate-server-cert and create-server-csr generate every key themselves,
+// RSA included, and sign its public key with the helpers below.
+func generatePrivateKey(spec KeySpec) (crypto.Signer, error) {
+	switch spec.Type {
+	case "ecdsa":
+		curve, err := ecdsaCurveByName(spec.ECDSACurve)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.GenerateKey(curve, cryptorand.Reader)
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(cryptorand.Reader)
+		return key, err
+	default:
+		return rsa.GenerateKey(cryptorand.Reader, spec.RSABits)
+	}
+}
+
+func ecdsaCurveByName(name string) (elliptic.Curve, error) {
+	switch name {
+	case "P384":
+		return elliptic.P384(), nil
+	case "P256", "":
+		return elliptic.P256(), nil
+	default:
+		return nil, fmt.Errorf("unsupported ecdsa-curve %q", name)
+	}
+}
+
+// marshalPrivateKeyPEM PKCS#8-encodes key as a PEM block.
+func marshalPrivateKeyPEM(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// writePrivateKeyPEM PKCS#8-encodes key and writes it to keyFile.
+func writePrivateKeyPEM(keyFile string, key crypto.Signer) error {
+	keyPEM, err := marshalPrivateKeyPEM(key)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(keyFile, keyPEM, 0600)
+}
+
+// writeCertChainPEM PEM-encodes chain (leaf first) and writes it to certFile.
+func writeCertChainPEM(certFile string, chain []*x509.Certificate) error {
+	buf := &bytes.Buffer{}
+	for _, cert := range chain {
+		if err := pem.Encode(buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(certFile, buf.Bytes(), 0644)
+}
+
+// signerKeyPair extracts the signing CA's leaf certificate and private key
+// as the (parent, signer) pair x509.CreateCertificate needs.
+func signerKeyPair(signerCert *libcrypto.TLSCertificateConfig) (*x509.Certificate, crypto.Signer, error) {
+	if len(signerCert.Certs) == 0 {
+		return nil, nil, errors.New("signing CA has no certificate")
+	}
+	signer, ok := signerCert.Key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("signing CA key of type %T cannot sign certificates", signerCert.Key)
+	}
+	return signerCert.Certs[0], signer, nil
+}
+
+// defaultKeyUsage and defaultExtKeyUsage are the key usages applied to
+// freshly issued server certificates. Rotation instead carries forward the
+// usages already on the certificate being rotated, via existingCertAttrs.
+var (
+	defaultKeyUsage    = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	defaultExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+)
+
+// serverCertTemplate builds the x509.Certificate template for a server
+// certificate valid for commonName, hostnames, and extraSANs for expireDays,
+// with the given key usages. commonName is taken as-is rather than derived
+// from hostnames, so callers that need to preserve an existing Subject
+// (rotation) don't have to invent one that a SAN set happens to agree with.
+func serverCertTemplate(commonName string, hostnames sets.String, extraSANs ExtraSANs, expireDays int, keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage) (*x509.Certificate, error) {
+	serial, err := cryptorand.Int(cryptorand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	dnsNames, ipAddresses := splitHostnames(hostnames)
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-1 * time.Hour),
+		NotAfter:              now.AddDate(0, 0, expireDays),
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
+		URIs:                  extraSANs.URIs,
+		EmailAddresses:        extraSANs.EmailAddresses,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		BasicConstraintsValid: true,
+	}, nil
+}
+
+// makeAndWriteServerCertWithKeySpec signs a new server certificate for
+// commonName/hostnames using keySpec's key algorithm and the given key
+// usages, writing the full chain (leaf plus the signer's own chain) to
+// certFile and the new key to keyFile.
+func makeAndWriteServerCertWithKeySpec(signerCert *libcrypto.TLSCertificateConfig, certFile, keyFile string, hostnames sets.String, commonName string, expireDays int, keySpec KeySpec, extraSANs ExtraSANs, keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage) (*libcrypto.TLSCertificateConfig, error) {
+	caCert, caSigner, err := signerKeyPair(signerCert)
+	if err != nil {
+		return nil, err
+	}
+	key, err := generatePrivateKey(keySpec)
+	if err != nil {
+		return nil, err
+	}
+	template, err := serverCertTemplate(commonName, hostnames, extraSANs, expireDays, keyUsage, extKeyUsage)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, caCert, key.Public(), caSigner)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	chain := append([]*x509.Certificate{leaf}, signerCert.Certs...)
+	if err := writeCertChainPEM(certFile, chain); err != nil {
+		return nil, err
+	}
+	if err := writePrivateKeyPEM(keyFile, key); err != nil {
+		return nil, err
+	}
+	return &libcrypto.TLSCertificateConfig{Certs: chain, Key: key}, nil
+}
+
+// ensureServerCertWithKeySpec behaves like makeAndWriteServerCertWithKeySpec,
+// except it leaves an existing cert/key pair at certFile/keyFile untouched
+// rather than overwriting it.
+func ensureServerCertWithKeySpec(signerCert *libcrypto.TLSCertificateConfig, certFile, keyFile string, hostnames sets.String, commonName string, expireDays int, keySpec KeySpec, extraSANs ExtraSANs, keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage) (*libcrypto.TLSCertificateConfig, bool, error) {
+	if existing, err := loadTLSCertificateConfig(certFile, keyFile); err == nil {
+		return existing, false, nil
+	}
+	ca, err := makeAndWriteServerCertWithKeySpec(signerCert, certFile, keyFile, hostnames, commonName, expireDays, keySpec, extraSANs, keyUsage, extKeyUsage)
+	return ca, true, err
+}
+
+// loadTLSCertificateConfig reads back a previously written cert/key pair,
+// accepting PKCS#8, PKCS#1 ("RSA PRIVATE KEY") and SEC 1 ("EC PRIVATE KEY")
+// keys, and failing if either file is missing or unparsable.
+func loadTLSCertificateConfig(certFile, keyFile string) (*libcrypto.TLSCertificateConfig, error) {
+	certBytes, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	rest := certBytes
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", certFile)
+	}
+
+	keyBytes, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, _ := pem.Decode(keyBytes)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("no key found in %s", keyFile)
+	}
+	var key interface{}
+	switch keyBlock.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(keyBlock.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &libcrypto.TLSCertificateConfig{Certs: certs, Key: key}, nil
+}
+
+// signCertificateRequest signs pub (typically a CSR's public key) against
+// commonName/hostnames and extraSANs, writing only the signed certificate to
+// certFile. No private key is generated or written: the caller already holds
+// the key that produced the request.
+func signCertificateRequest(signerCert *libcrypto.TLSCertificateConfig, certFile string, pub crypto.PublicKey, hostnames sets.String, commonName string, expireDays int, extraSANs ExtraSANs, keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage) (*libcrypto.TLSCertificateConfig, error) {
+	caCert, caSigner, err := signerKeyPair(signerCert)
+	if err != nil {
+		return nil, err
+	}
+	template, err := serverCertTemplate(commonName, hostnames, extraSANs, expireDays, keyUsage, extKeyUsage)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, caCert, pub, caSigner)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	chain := append([]*x509.Certificate{leaf}, signerCert.Certs...)
+	if err := writeCertChainPEM(certFile, chain); err != nil {
+		return nil, err
+	}
+	return &libcrypto.TLSCertificateConfig{Certs: chain}, nil
+}